@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/gatariee/gocheck/scanner"
+)
+
+// Exit codes for "gocheck ci", mirroring the CI-check convention of
+// distinguishing a failed gate (1) from a usage or runtime error (2).
+const (
+	exitOK                = 0
+	exitThresholdExceeded = 1
+	exitUsageError        = 2
+)
+
+var failOnThresholds = map[string]scanner.Severity{
+	"any":      scanner.SeveritySuspicion,
+	"heur":     scanner.SeverityHeuristic,
+	"detected": scanner.SeverityDetected,
+}
+
+type ciFinding struct {
+	Offset    int    `json:"offset"`
+	Length    int    `json:"length"`
+	Signature string `json:"signature"`
+	Severity  string `json:"severity"`
+	Member    string `json:"member,omitempty"`
+}
+
+type ciEngineReport struct {
+	Engine   string      `json:"engine"`
+	File     string      `json:"file"`
+	Findings []ciFinding `json:"findings"`
+	Duration string      `json:"duration"`
+}
+
+func runCI(args []string) int {
+	fs := flag.NewFlagSet("ci", flag.ContinueOnError)
+	output := fs.String("output", "", "write the JSON report here instead of stdout")
+	failOn := fs.String("fail-on", "any", "minimum severity that fails the build: any, heur, detected")
+	minSignatures := fs.Int("min-signatures", 1, "minimum number of qualifying findings required to fail the build")
+	debug := fs.Bool("debug", false, "enable verbose scan logging")
+	if err := fs.Parse(args); err != nil {
+		return exitUsageError
+	}
+
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: gocheck ci [flags] <file>")
+		return exitUsageError
+	}
+	file := fs.Arg(0)
+
+	threshold, ok := failOnThresholds[*failOn]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "gocheck ci: unknown --fail-on value %q (want any, heur, or detected)\n", *failOn)
+		return exitUsageError
+	}
+
+	engines := scanner.Registered()
+	reports := make([]ciEngineReport, 0, len(engines))
+	qualifying := 0
+
+	for _, engine := range engines {
+		if _, available := engine.Available(); !available {
+			continue
+		}
+
+		start := time.Now()
+		findings, _, err := scanner.CollectFindings(context.Background(), engine, file, *debug)
+		duration := time.Since(start)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "gocheck ci: %s: %v\n", engine.Name(), err)
+			continue
+		}
+
+		report := ciEngineReport{Engine: engine.Name(), File: file, Duration: duration.String()}
+		for _, f := range findings {
+			report.Findings = append(report.Findings, ciFinding{
+				Offset:    f.Offset,
+				Length:    f.Length,
+				Signature: f.Signature,
+				Severity:  string(f.Severity),
+				Member:    f.Member,
+			})
+			if f.Severity.Meets(threshold) {
+				qualifying++
+			}
+		}
+		reports = append(reports, report)
+	}
+
+	w := os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "gocheck ci: %v\n", err)
+			return exitUsageError
+		}
+		defer f.Close()
+		w = f
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(reports); err != nil {
+		fmt.Fprintf(os.Stderr, "gocheck ci: %v\n", err)
+		return exitUsageError
+	}
+
+	if qualifying >= *minSignatures {
+		return exitThresholdExceeded
+	}
+	return exitOK
+}
@@ -0,0 +1,24 @@
+// Command gocheck is the CLI entrypoint for GoCheck's scanning engines.
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: gocheck <ci|scan> [flags] <file>")
+		os.Exit(exitUsageError)
+	}
+
+	switch os.Args[1] {
+	case "ci":
+		os.Exit(runCI(os.Args[2:]))
+	case "scan":
+		os.Exit(runScan(os.Args[2:]))
+	default:
+		fmt.Fprintf(os.Stderr, "gocheck: unknown command %q\n", os.Args[1])
+		os.Exit(exitUsageError)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/gatariee/gocheck/scanner"
+)
+
+// runScan drives "gocheck scan", RunAll's single invocation producing one
+// consolidated, bisected report across every locally installed engine,
+// surfaced directly as a command.
+func runScan(args []string) int {
+	fs := flag.NewFlagSet("scan", flag.ContinueOnError)
+	debug := fs.Bool("debug", false, "enable verbose scan logging")
+	if err := fs.Parse(args); err != nil {
+		return exitUsageError
+	}
+
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: gocheck scan [flags] <file>")
+		return exitUsageError
+	}
+	file := fs.Arg(0)
+
+	engines := scanner.Registered()
+	reports, err := scanner.RunAll(file, engines, scanner.Options{Debug: *debug})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gocheck scan: %v\n", err)
+		return exitUsageError
+	}
+
+	malicious := false
+	for _, r := range reports {
+		if r.Err != nil {
+			fmt.Printf("%-10s skipped: %v\n", r.Engine, r.Err)
+			continue
+		}
+		if len(r.Findings) == 0 {
+			fmt.Printf("%-10s malicious: false\n", r.Engine)
+			continue
+		}
+
+		malicious = true
+		fmt.Printf("%-10s malicious: true\n", r.Engine)
+		for _, f := range r.Findings {
+			if f.Length > 0 {
+				fmt.Printf("  offset 0x%X, length %d, severity %s [%s]\n", f.Offset, f.Length, f.Severity, f.Signature)
+			} else {
+				fmt.Printf("  severity %s [%s]\n", f.Severity, f.Signature)
+			}
+		}
+	}
+
+	if malicious {
+		return exitThresholdExceeded
+	}
+	return exitOK
+}
@@ -0,0 +1,226 @@
+// Package input abstracts over the different ways a payload under test can
+// be packaged on disk - a raw binary, or one bundled inside a container
+// that's common for delivery testing, like a dropper's .zip or an .iso.
+package input
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Kind identifies the container format Open detected for a given path.
+type Kind int
+
+const (
+	KindRaw Kind = iota
+	KindZip
+	KindTar
+	KindTarGz
+	KindISO
+	Kind7z
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindZip:
+		return "zip"
+	case KindTar:
+		return "tar"
+	case KindTarGz:
+		return "tar.gz"
+	case KindISO:
+		return "iso"
+	case Kind7z:
+		return "7z"
+	default:
+		return "raw"
+	}
+}
+
+// Member is one file extracted from a container, kept in memory so callers
+// can bisect it in isolation and prefix findings with its archive path.
+type Member struct {
+	Name string
+	Data []byte
+}
+
+// Open returns a virtual file handle for path: a read-only view over the
+// whole file plus the Kind detected from its name. A raw file's handle is
+// the file itself; a recognised container's handle is the archive as a
+// whole, and callers should use Members to iterate its contents instead of
+// scanning the container directly.
+func Open(path string) (io.ReaderAt, int64, Kind, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, 0, KindRaw, err
+	}
+
+	return bytes.NewReader(data), int64(len(data)), detectKind(path), nil
+}
+
+// detectKind classifies path by its extension. GoCheck payloads are
+// produced by the operator, not untrusted third parties, so sniffing the
+// file extension is sufficient - there's no adversary trying to disguise
+// the container format.
+func detectKind(path string) Kind {
+	lower := strings.ToLower(path)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return KindZip
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return KindTarGz
+	case strings.HasSuffix(lower, ".tar"):
+		return KindTar
+	case strings.HasSuffix(lower, ".iso"):
+		return KindISO
+	case strings.HasSuffix(lower, ".7z"):
+		return Kind7z
+	default:
+		return KindRaw
+	}
+}
+
+// Members extracts every regular file inside the container at path. For a
+// raw file, it returns a single member wrapping the whole file.
+func Members(path string, kind Kind) ([]Member, error) {
+	switch kind {
+	case KindZip:
+		return zipMembers(path)
+	case KindTar:
+		return tarMembers(path, false)
+	case KindTarGz:
+		return tarMembers(path, true)
+	case KindISO, Kind7z:
+		return sevenZipMembers(path)
+	default:
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return []Member{{Name: filepath.Base(path), Data: data}}, nil
+	}
+}
+
+func zipMembers(path string) ([]Member, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	members := make([]Member, 0, len(r.File))
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		members = append(members, Member{Name: f.Name, Data: data})
+	}
+
+	return members, nil
+}
+
+func tarMembers(path string, gzipped bool) ([]Member, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if gzipped {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	var members []Member
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+
+		members = append(members, Member{Name: hdr.Name, Data: data})
+	}
+
+	return members, nil
+}
+
+// sevenZipMembers shells out to the 7z CLI to extract an ISO or 7z
+// container, the same way Kaspersky scanning shells out to avp.com - the
+// standard library has no ISO9660 or 7z reader, and both formats are
+// common for packaging payloads in delivery testing.
+func sevenZipMembers(path string) ([]Member, error) {
+	tempDir, err := os.MkdirTemp("", "gocheck-input-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tempDir)
+
+	cmd := exec.Command("7z", "x", "-y", fmt.Sprintf("-o%s", tempDir), path)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("input: 7z extraction failed: %w: %s", err, out)
+	}
+
+	var members []Member
+	err = filepath.Walk(tempDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(tempDir, p)
+		if err != nil {
+			return err
+		}
+
+		members = append(members, Member{Name: rel, Data: data})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
@@ -0,0 +1,58 @@
+// Package log is GoCheck's leveled logging and scan-progress surface. It
+// replaces the ad-hoc utils.PrintErr/PrintDebug/PrintOk helpers and the
+// ticker-driven progress goroutine that used to live in scanner, neither
+// of which could be swapped out for a non-interactive CI run.
+package log
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Logger is GoCheck's leveled logging surface.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+	Statf(format string, args ...interface{})
+}
+
+// terminalLogger prints coloured, leveled lines to a writer.
+type terminalLogger struct {
+	w     io.Writer
+	debug bool
+}
+
+// NewTerminal returns a Logger that writes coloured, leveled lines to w.
+// Debugf is a no-op unless debug is true.
+func NewTerminal(w io.Writer, debug bool) Logger {
+	return &terminalLogger{w: w, debug: debug}
+}
+
+func (l *terminalLogger) Debugf(format string, args ...interface{}) {
+	if !l.debug {
+		return
+	}
+	fmt.Fprintf(l.w, "\033[90m[debug] %s\033[0m\n", fmt.Sprintf(format, args...))
+}
+
+func (l *terminalLogger) Infof(format string, args ...interface{}) {
+	fmt.Fprintf(l.w, "\033[34m[*] %s\033[0m\n", fmt.Sprintf(format, args...))
+}
+
+func (l *terminalLogger) Warnf(format string, args ...interface{}) {
+	fmt.Fprintf(l.w, "\033[33m[!] %s\033[0m\n", fmt.Sprintf(format, args...))
+}
+
+func (l *terminalLogger) Errorf(format string, args ...interface{}) {
+	fmt.Fprintf(l.w, "\033[31m[-] %s\033[0m\n", fmt.Sprintf(format, args...))
+}
+
+func (l *terminalLogger) Statf(format string, args ...interface{}) {
+	fmt.Fprintf(l.w, "\033[32m[+] %s\033[0m\n", fmt.Sprintf(format, args...))
+}
+
+// Default is a terminal logger writing to stderr with debug logging off.
+var Default Logger = NewTerminal(os.Stderr, false)
@@ -0,0 +1,148 @@
+package scanner
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/gatariee/gocheck/log"
+)
+
+func TestClassifySeverity(t *testing.T) {
+	cases := []struct {
+		name   string
+		output string
+		want   Severity
+	}{
+		{"clean", "object OK", SeverityNone},
+		{"suspicion only", "object C:\\payload.exe\tsuspicion", SeveritySuspicion},
+		{"heuristic signature", "object C:\\payload.exe\tsuspicion\tHEUR:Trojan.Win32.Generic", SeverityHeuristic},
+		{"detected", "object C:\\payload.exe\tsuspicion\tdetected Trojan.Generic", SeverityDetected},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := ClassifySeverity(c.output); got != c.want {
+				t.Errorf("ClassifySeverity(%q) = %q, want %q", c.output, got, c.want)
+			}
+		})
+	}
+}
+
+func TestSeverityMeets(t *testing.T) {
+	if !SeverityDetected.Meets(SeveritySuspicion) {
+		t.Error("detected should meet the suspicion threshold")
+	}
+	if SeveritySuspicion.Meets(SeverityDetected) {
+		t.Error("suspicion should not meet the detected threshold")
+	}
+	if SeverityHeuristic.Meets(SeverityDetected) {
+		t.Error("a bare heuristic signature should not meet the definite-detection threshold")
+	}
+	if !SeverityDetected.Meets(SeverityHeuristic) {
+		t.Error("detected should meet the heuristic threshold")
+	}
+}
+
+// newTestBisector builds a bisector driven by scan instead of a real
+// Kaspersky invocation, so split/shrink can be exercised against a
+// synthetic malicious predicate.
+func newTestBisector(original []byte, scan func([]byte) (string, error)) *bisector {
+	return &bisector{
+		original: original,
+		logger:   log.NewTerminal(io.Discard, false),
+		progress: log.NopProgress{},
+		scan:     scan,
+	}
+}
+
+// markerScan reports a range malicious only when it contains the full
+// marker sequence, the same way a real signature only fires against the
+// complete byte pattern rather than a fragment of it.
+func markerScan(marker []byte) func([]byte) (string, error) {
+	return func(data []byte) (string, error) {
+		if bytes.Contains(data, marker) {
+			return "object test.bin\tsuspicion\tdetected HEUR:Test.Marker", nil
+		}
+		return "object test.bin\tOK", nil
+	}
+}
+
+func TestSplitLeftOnly(t *testing.T) {
+	b := newTestBisector([]byte{0xAA, 0x00}, markerScan([]byte{0xAA}))
+
+	findings, err := b.split(0, 2)
+	if err != nil {
+		t.Fatalf("split: %v", err)
+	}
+	if len(findings) != 1 || findings[0].Offset != 0 || findings[0].Length != 1 {
+		t.Fatalf("got %+v, want a single finding at offset 0, length 1", findings)
+	}
+}
+
+func TestSplitRightOnly(t *testing.T) {
+	b := newTestBisector([]byte{0x00, 0xAA}, markerScan([]byte{0xAA}))
+
+	findings, err := b.split(0, 2)
+	if err != nil {
+		t.Fatalf("split: %v", err)
+	}
+	if len(findings) != 1 || findings[0].Offset != 1 || findings[0].Length != 1 {
+		t.Fatalf("got %+v, want a single finding at offset 1, length 1", findings)
+	}
+}
+
+func TestSplitBothHalves(t *testing.T) {
+	b := newTestBisector([]byte{0xAA, 0xAA}, markerScan([]byte{0xAA}))
+
+	findings, err := b.split(0, 2)
+	if err != nil {
+		t.Fatalf("split: %v", err)
+	}
+	if len(findings) != 2 {
+		t.Fatalf("got %d findings, want 2 (one per malicious byte)", len(findings))
+	}
+}
+
+func TestSplitStraddleRescansInsteadOfFabricating(t *testing.T) {
+	// Neither single byte contains the full two-byte marker on its own,
+	// but the combined range does - the signature straddles the split
+	// point at mid=1.
+	b := newTestBisector([]byte{0xDE, 0xAD}, markerScan([]byte{0xDE, 0xAD}))
+
+	findings, err := b.split(0, 2)
+	if err != nil {
+		t.Fatalf("split: %v", err)
+	}
+	if len(findings) != 1 || findings[0].Offset != 0 || findings[0].Length != 2 {
+		t.Fatalf("got %+v, want a single finding spanning the whole straddling range", findings)
+	}
+	// A concatenation of the two (clean) half-outputs would contain neither
+	// "suspicion" nor a HEUR: signature, so this only passes if the
+	// straddle branch actually rescanned [0, 2) for genuine evidence.
+	if findings[0].Severity == SeverityNone {
+		t.Error("straddling finding classified as SeverityNone - evidence was fabricated instead of rescanned")
+	}
+	if findings[0].Signature == "No signature found" {
+		t.Error("straddling finding has no signature - evidence was fabricated instead of rescanned")
+	}
+}
+
+func TestShrinkNarrowsAcrossOriginalMidpoint(t *testing.T) {
+	// The only byte sequence markerScan flags is at [3, 6) - straddling
+	// the midpoint (4) of the full [0, 8) range.
+	original := make([]byte, 8)
+	copy(original[3:6], []byte{0xDE, 0xAD, 0xBE})
+	b := newTestBisector(original, markerScan([]byte{0xDE, 0xAD, 0xBE}))
+
+	lo, hi, output, err := b.shrink(0, 8)
+	if err != nil {
+		t.Fatalf("shrink: %v", err)
+	}
+	if lo != 3 || hi != 6 {
+		t.Fatalf("shrink(0, 8) = [%d, %d), want [3, 6) - it must narrow past the original midpoint", lo, hi)
+	}
+	if output == "" {
+		t.Error("shrink returned no output for a window it confirmed malicious")
+	}
+}
@@ -0,0 +1,153 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// ScanResult is the outcome of a single engine scanning a single file or
+// file shard.
+type ScanResult struct {
+	Malicious bool
+	Raw       string
+}
+
+// Scanner is implemented by every antivirus engine GoCheck knows how to
+// drive. KasperskyScanner is the first implementation; Defender and
+// ClamAV are expected to follow the same shape.
+type Scanner interface {
+	// Name identifies the engine, e.g. "kaspersky".
+	Name() string
+	// Available reports whether the engine is installed locally and, if
+	// so, the path to its CLI/scan binary.
+	Available() (path string, ok bool)
+	// Scan runs the engine against path and reports whether it flagged
+	// the file, along with the raw engine output.
+	Scan(ctx context.Context, path string) (ScanResult, error)
+	// ParseSignature extracts the signature name(s), if any, from a raw
+	// engine output string.
+	ParseSignature(raw string) []string
+}
+
+// Bisector is implemented by engines that can isolate multiple malicious
+// regions within a file, rather than reporting a single pass/fail verdict.
+// KasperskyScanner implements it via ScanInput.
+type Bisector interface {
+	BisectFindings(path string, debug bool) ([]Finding, error)
+}
+
+// Options configures a RunAll invocation.
+type Options struct {
+	Debug      bool
+	MaxWorkers int
+}
+
+// EngineReport is one engine's result from a RunAll invocation.
+type EngineReport struct {
+	Engine   string
+	Path     string
+	Result   ScanResult
+	Findings []Finding
+	Err      error
+}
+
+// CollectFindings runs engine against file, preferring its Bisector findings
+// (with isolated offsets) when it implements one, and falling back to a
+// single pass/fail Scan otherwise.
+func CollectFindings(ctx context.Context, engine Scanner, file string, debug bool) ([]Finding, ScanResult, error) {
+	if b, ok := engine.(Bisector); ok {
+		findings, err := b.BisectFindings(file, debug)
+		if err != nil {
+			return nil, ScanResult{}, err
+		}
+		return findings, ScanResult{Malicious: len(findings) > 0}, nil
+	}
+
+	result, err := engine.Scan(ctx, file)
+	if err != nil {
+		return nil, ScanResult{}, err
+	}
+	if !result.Malicious {
+		return nil, result, nil
+	}
+
+	signature := "No signature found"
+	if sigs := engine.ParseSignature(result.Raw); len(sigs) > 0 {
+		signature = sigs[0]
+	}
+
+	return []Finding{{
+		Signature: signature,
+		Severity:  ClassifySeverity(result.Raw),
+	}}, result, nil
+}
+
+var (
+	registryMu sync.Mutex
+	registry   = map[string]Scanner{}
+)
+
+// Register adds an engine to the default registry so it is picked up by
+// callers that scan with every known engine.
+func Register(s Scanner) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[s.Name()] = s
+}
+
+// Registered returns every engine registered via Register.
+func Registered() []Scanner {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	engines := make([]Scanner, 0, len(registry))
+	for _, s := range registry {
+		engines = append(engines, s)
+	}
+	return engines
+}
+
+const defaultMaxWorkers = 4
+
+// RunAll bisects file against every engine in engines concurrently, using a
+// bounded worker pool, and returns one consolidated report per engine -
+// isolated findings for engines that implement Bisector, a single pass/fail
+// verdict otherwise. An engine that isn't installed still gets a report,
+// with Err set.
+func RunAll(file string, engines []Scanner, opts Options) ([]EngineReport, error) {
+	if len(engines) == 0 {
+		return nil, fmt.Errorf("scanner: no engines to run")
+	}
+
+	maxWorkers := opts.MaxWorkers
+	if maxWorkers <= 0 {
+		maxWorkers = defaultMaxWorkers
+	}
+
+	sem := make(chan struct{}, maxWorkers)
+	reports := make([]EngineReport, len(engines))
+
+	var wg sync.WaitGroup
+	for i, engine := range engines {
+		wg.Add(1)
+		go func(i int, engine Scanner) {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			path, ok := engine.Available()
+			if !ok {
+				reports[i] = EngineReport{Engine: engine.Name(), Err: fmt.Errorf("%s: not installed", engine.Name())}
+				return
+			}
+
+			findings, result, err := CollectFindings(context.Background(), engine, file, opts.Debug)
+			reports[i] = EngineReport{Engine: engine.Name(), Path: path, Result: result, Findings: findings, Err: err}
+		}(i, engine)
+	}
+	wg.Wait()
+
+	return reports, nil
+}
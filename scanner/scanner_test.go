@@ -0,0 +1,80 @@
+package scanner
+
+import (
+	"context"
+	"testing"
+)
+
+// fakeScanner is a minimal Scanner used to exercise the registry and RunAll
+// without shelling out to a real AV engine.
+type fakeScanner struct {
+	name      string
+	available bool
+	malicious bool
+}
+
+func (f fakeScanner) Name() string { return f.name }
+
+func (f fakeScanner) Available() (string, bool) {
+	if !f.available {
+		return "", false
+	}
+	return "/usr/bin/" + f.name, true
+}
+
+func (f fakeScanner) Scan(ctx context.Context, path string) (ScanResult, error) {
+	return ScanResult{Malicious: f.malicious, Raw: path}, nil
+}
+
+func (f fakeScanner) ParseSignature(raw string) []string { return nil }
+
+func TestRunAllReportsEveryEngine(t *testing.T) {
+	engines := []Scanner{
+		fakeScanner{name: "present-clean", available: true, malicious: false},
+		fakeScanner{name: "present-dirty", available: true, malicious: true},
+		fakeScanner{name: "absent", available: false},
+	}
+
+	reports, err := RunAll("payload.exe", engines, Options{MaxWorkers: 2})
+	if err != nil {
+		t.Fatalf("RunAll: %v", err)
+	}
+	if len(reports) != len(engines) {
+		t.Fatalf("got %d reports, want %d", len(reports), len(engines))
+	}
+
+	byEngine := make(map[string]EngineReport, len(reports))
+	for _, r := range reports {
+		byEngine[r.Engine] = r
+	}
+
+	if r := byEngine["present-clean"]; r.Err != nil || r.Result.Malicious {
+		t.Errorf("present-clean: got %+v, want clean report with no error", r)
+	}
+	if r := byEngine["present-dirty"]; r.Err != nil || !r.Result.Malicious {
+		t.Errorf("present-dirty: got %+v, want malicious report with no error", r)
+	}
+	if r := byEngine["absent"]; r.Err == nil {
+		t.Errorf("absent: got no error, want one reporting the engine isn't installed")
+	}
+}
+
+func TestRunAllRejectsEmptyEngineList(t *testing.T) {
+	if _, err := RunAll("payload.exe", nil, Options{}); err == nil {
+		t.Fatal("RunAll with no engines: got nil error, want one")
+	}
+}
+
+func TestRegisterAndRegistered(t *testing.T) {
+	Register(fakeScanner{name: "registry-test", available: true})
+
+	found := false
+	for _, s := range Registered() {
+		if s.Name() == "registry-test" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("Registered() does not contain the engine just registered")
+	}
+}